@@ -0,0 +1,10 @@
+// Package configuration defines the registry's on-disk configuration schema.
+package configuration
+
+// Configuration is the root of the registry's configuration. Only the
+// sections this tree's code consumes are modeled here.
+type Configuration struct {
+	// Mirror configures pull-through mirroring of one or more upstream
+	// registries into this registry.
+	Mirror Mirror `yaml:"mirror,omitempty"`
+}
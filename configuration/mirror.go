@@ -0,0 +1,55 @@
+package configuration
+
+import "crypto/tls"
+
+// Mirror configures pull-through mirroring of one or more upstream
+// registries.
+type Mirror struct {
+	// Sources lists the upstream registries this registry mirrors from. The
+	// source whose RepositoryPrefix is the longest match for a requested
+	// repository name is used; an empty RepositoryPrefix matches any
+	// repository not claimed by a more specific source.
+	Sources []MirrorSource `yaml:"sources,omitempty"`
+
+	// AllPlatforms, when true, mirrors every platform in an OCI image index
+	// by default. Otherwise only the platform the registry itself runs on is
+	// mirrored, unless a request's ?platform= selector overrides it.
+	AllPlatforms bool `yaml:"allplatforms,omitempty"`
+
+	// StoreDir, if set, persists mirror records under this directory so
+	// in-flight and historical mirror state survives a registry restart. An
+	// empty StoreDir disables persistence.
+	StoreDir string `yaml:"storedir,omitempty"`
+
+	// StoreDriver selects the MirrorStore backend: "bolt" (the default) or
+	// "filesystem".
+	StoreDriver string `yaml:"storedriver,omitempty"`
+}
+
+// MirrorSource configures one upstream registry to mirror from.
+type MirrorSource struct {
+	// Name identifies this source for logging and for caching the
+	// authChallenger built for it.
+	Name string `yaml:"name"`
+
+	// Host is the upstream registry's base URL, e.g.
+	// "https://registry-1.docker.io".
+	Host string `yaml:"host"`
+
+	// Username and Password authenticate against Host, if it requires them.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// TLS is the client TLS configuration used to connect to Host. A nil
+	// value uses http.DefaultTransport's default TLS behavior.
+	TLS *tls.Config `yaml:"-"`
+
+	// RepositoryPrefix restricts this source to repository names with this
+	// prefix. An empty prefix matches every repository not claimed by a
+	// more specific source.
+	RepositoryPrefix string `yaml:"repositoryprefix,omitempty"`
+
+	// RewritePrefix, if set, replaces a matched RepositoryPrefix with this
+	// value when building the repository name requested against Host.
+	RewritePrefix string `yaml:"rewriteprefix,omitempty"`
+}
@@ -2,13 +2,21 @@ package v2
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/distribution/reference"
 	"github.com/docker/distribution"
 	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/errgroup"
 )
 
 type Phase string
@@ -29,6 +37,9 @@ const (
 	TagNotFound      Code = 104
 	Unknown          Code = 105
 	UnknownMediaType Code = 106
+	// BlobRetrying means a blob download failed and is being retried; the
+	// overall mirror has not failed yet.
+	BlobRetrying Code = 107
 )
 
 type Blob struct {
@@ -36,6 +47,130 @@ type Blob struct {
 	Size    int64  `json:"size"`
 	Error   error  `json:"error"`
 	Speed   string `json:"speed"`
+
+	// WriterID identifies the in-progress local blob upload session, if any,
+	// so a retry (or a later ImageMirror.MirrorImage call against the same
+	// partially-written blob) can resume instead of starting from byte zero.
+	WriterID string `json:"writerId,omitempty"`
+}
+
+// blobJSON is the JSON wire representation of Blob. Error is carried as a
+// plain string rather than the error interface: a concrete error value
+// marshals to "{}" (its unexported fields are invisible to encoding/json),
+// and unmarshaling "{}" back into an error-typed field always fails. Blob's
+// own (Un)MarshalJSON translate to and from this shape so both the API
+// responses and the MirrorStore round-trip survive a blob that failed.
+type blobJSON struct {
+	Precent  int    `json:"precent"`
+	Size     int64  `json:"size"`
+	Error    string `json:"error,omitempty"`
+	Speed    string `json:"speed"`
+	WriterID string `json:"writerId,omitempty"`
+}
+
+func (b Blob) MarshalJSON() ([]byte, error) {
+	bj := blobJSON{Precent: b.Precent, Size: b.Size, Speed: b.Speed, WriterID: b.WriterID}
+	if b.Error != nil {
+		bj.Error = b.Error.Error()
+	}
+	return json.Marshal(bj)
+}
+
+func (b *Blob) UnmarshalJSON(data []byte) error {
+	var bj blobJSON
+	if err := json.Unmarshal(data, &bj); err != nil {
+		return err
+	}
+	b.Precent = bj.Precent
+	b.Size = bj.Size
+	b.Speed = bj.Speed
+	b.WriterID = bj.WriterID
+	b.Error = nil
+	if bj.Error != "" {
+		b.Error = errors.New(bj.Error)
+	}
+	return nil
+}
+
+// BackoffPolicy configures the per-blob retry behavior: attempts are spaced
+// by InitialDelay*Factor^attempt, jittered and capped at MaxDelay.
+type BackoffPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	Factor       float64
+	MaxDelay     time.Duration
+}
+
+// DefaultBlobBackoff is used by ImageMirror.MirrorImage unless overridden via
+// ImageMirror.Backoff.
+var DefaultBlobBackoff = BackoffPolicy{
+	MaxAttempts:  5,
+	InitialDelay: 500 * time.Millisecond,
+	Factor:       2,
+	MaxDelay:     30 * time.Second,
+}
+
+func (p BackoffPolicy) delay(attempt int) time.Duration {
+	d := float64(p.InitialDelay) * math.Pow(p.Factor, float64(attempt))
+	if max := float64(p.MaxDelay); d > max {
+		d = max
+	}
+	// full jitter: uniform in [0, d]
+	return time.Duration(d * rand.Float64())
+}
+
+// PlatformSelector matches a subset of the platforms in an OCI image index,
+// following the OCI image-spec platform matcher: an empty OS or Architecture
+// is a wildcard, and Variant is only compared when the selector specifies one.
+type PlatformSelector struct {
+	OS           string `json:"os,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// ParsePlatformSelector parses a "os/arch" or "os/arch/variant" string as
+// used by the ?platform= query parameter.
+func ParsePlatformSelector(s string) (PlatformSelector, error) {
+	parts := strings.Split(s, "/")
+	switch len(parts) {
+	case 2:
+		return PlatformSelector{OS: parts[0], Architecture: parts[1]}, nil
+	case 3:
+		return PlatformSelector{OS: parts[0], Architecture: parts[1], Variant: parts[2]}, nil
+	default:
+		return PlatformSelector{}, fmt.Errorf("invalid platform %q, expected os/arch[/variant]", s)
+	}
+}
+
+// Matches reports whether p satisfies the selector.
+func (s PlatformSelector) Matches(p *v1.Platform) bool {
+	if p == nil {
+		return false
+	}
+	if s.OS != "" && s.OS != p.OS {
+		return false
+	}
+	if s.Architecture != "" && s.Architecture != p.Architecture {
+		return false
+	}
+	if s.Variant != "" && s.Variant != p.Variant {
+		return false
+	}
+	return true
+}
+
+// MatchesAnyPlatform reports whether p satisfies at least one of selectors.
+// An empty selector list matches every platform.
+func MatchesAnyPlatform(p *v1.Platform, selectors []PlatformSelector) bool {
+	if len(selectors) == 0 {
+		return true
+	}
+	for _, s := range selectors {
+		if s.Matches(p) {
+			return true
+		}
+	}
+	return false
 }
 
 type Image struct {
@@ -58,12 +193,51 @@ type ImageMirror struct {
 	Code       Code      `json:"code"`
 	Images     []*Image  `json:"images"`
 
+	// Platforms restricts an image-index mirror to the matching platforms,
+	// per PlatformSelector. Empty means mirror every platform in the index.
+	Platforms []PlatformSelector `json:"platforms,omitempty"`
+
 	mux          sync.Mutex
 	name         reference.Named
 	blobToImages map[digest.Digest]int
+
+	// resumeWriters carries forward the upload-session ID of each
+	// partially-transferred blob across a ResetToPending, so the next
+	// MirrorImage call resumes from that blob's prior offset instead of
+	// starting over. Populated by ResetToPending and consumed (and cleared
+	// per digest) by MirrorImage.
+	resumeWriters map[digest.Digest]string
+
+	// skippedPlatforms is the set of platform strings already folded into
+	// Message by NoteSkippedPlatform, so a later platform whose string
+	// happens to be a substring of one already recorded (e.g. "linux/arm"
+	// after "linux/arm64") still gets its own note instead of being treated
+	// as a duplicate.
+	skippedPlatforms []string
+
+	subs      map[int]chan Snapshot
+	nextSubID int
+
+	// Backoff is the retry policy applied to blob downloads. It is not
+	// persisted; a reloaded record falls back to DefaultBlobBackoff.
+	Backoff BackoffPolicy `json:"-"`
 }
 
-func NewImageMirror(name reference.Named, tag string) *ImageMirror {
+// Snapshot is a point-in-time, detached copy of an ImageMirror's state. It is
+// what gets handed to subscribers so they can read it without holding the
+// ImageMirror's lock.
+type Snapshot struct {
+	Name       string             `json:"name"`
+	Tag        string             `json:"tag"`
+	CreateTime time.Time          `json:"createTime"`
+	Phase      Phase              `json:"phase"`
+	Message    string             `json:"message"`
+	Code       Code               `json:"code"`
+	Images     []*Image           `json:"images"`
+	Platforms  []PlatformSelector `json:"platforms,omitempty"`
+}
+
+func NewImageMirror(name reference.Named, tag string, platforms ...PlatformSelector) *ImageMirror {
 	return &ImageMirror{
 		Name:         name.Name(),
 		Tag:          tag,
@@ -71,6 +245,8 @@ func NewImageMirror(name reference.Named, tag string) *ImageMirror {
 		Phase:        Pending,
 		name:         name,
 		blobToImages: make(map[digest.Digest]int),
+		Backoff:      DefaultBlobBackoff,
+		Platforms:    platforms,
 	}
 }
 
@@ -78,20 +254,145 @@ func (im *ImageMirror) Named() reference.Named {
 	return im.name
 }
 
-func (im *ImageMirror) IsPending() bool {
+// Hydrate restores the unexported fields that a MirrorStore cannot persist
+// (reference.Named has no JSON representation) after an ImageMirror is loaded
+// back from storage. It must be called before the record is used for mirroring.
+func (im *ImageMirror) Hydrate() error {
+	name, err := reference.WithName(im.Name)
+	if err != nil {
+		return fmt.Errorf("ImageMirror %s: parse name: %s", im.Name, err)
+	}
+	im.name = name
+	if im.blobToImages == nil {
+		im.blobToImages = make(map[digest.Digest]int)
+	}
+	if im.Backoff == (BackoffPolicy{}) {
+		im.Backoff = DefaultBlobBackoff
+	}
+	return nil
+}
+
+// ResetToPending clears prior progress and returns the record to Pending, so
+// it is mirrored again from scratch. Used when a restart finds a Mirroring
+// record whose blobs are not all present in the local repository.
+//
+// Before discarding im.Images, it saves the upload-session WriterID of every
+// blob that was only partially transferred, so the restart this is called
+// from can still resume each blob from its prior offset rather than
+// re-downloading it from byte zero.
+func (im *ImageMirror) ResetToPending() {
+	im.Phase = Pending
+	im.Code = 0
+	im.Message = ""
+	if im.resumeWriters == nil {
+		im.resumeWriters = make(map[digest.Digest]string)
+	}
+	for _, img := range im.Images {
+		for dgst, b := range img.Blobs {
+			if b.WriterID != "" && b.Precent < 100 {
+				im.resumeWriters[dgst] = b.WriterID
+			}
+		}
+	}
+	im.Images = nil
+	im.blobToImages = make(map[digest.Digest]int)
+	im.skippedPlatforms = nil
+}
+
+// Subscribe registers the caller for snapshot updates as the mirror
+// progresses. The returned channel receives the current snapshot immediately
+// and a new one after every publish; the returned func must be called to
+// unsubscribe and release the channel once the caller is done.
+func (im *ImageMirror) Subscribe() (<-chan Snapshot, func()) {
+	ch := make(chan Snapshot, 8)
+
 	im.mux.Lock()
+	if im.subs == nil {
+		im.subs = make(map[int]chan Snapshot)
+	}
+	id := im.nextSubID
+	im.nextSubID++
+	im.subs[id] = ch
+	snap := im.snapshot()
 	im.mux.Unlock()
-	isPending := im.Phase == Pending
-	if isPending {
-		im.Phase = Mirroring
+
+	ch <- snap
+
+	unsubscribe := func() {
+		im.mux.Lock()
+		if c, ok := im.subs[id]; ok {
+			delete(im.subs, id)
+			close(c)
+		}
+		im.mux.Unlock()
+	}
+	return ch, unsubscribe
+}
+
+// snapshot returns a deep copy of the current state. Callers must hold
+// im.mux. A shallow copy would still share the *Image/*Blob pointers with the
+// live record, so a subscriber (or the non-streaming JSON response) could
+// race the blob-transfer goroutines that keep mutating them; this makes the
+// returned Snapshot safe to read, or json.Marshal, without holding any lock.
+func (im *ImageMirror) snapshot() Snapshot {
+	images := make([]*Image, len(im.Images))
+	for i, img := range im.Images {
+		blobs := make(map[digest.Digest]*Blob, len(img.Blobs))
+		for d, b := range img.Blobs {
+			cp := *b
+			blobs[d] = &cp
+		}
+		imgCopy := *img
+		imgCopy.Blobs = blobs
+		images[i] = &imgCopy
+	}
+	return Snapshot{
+		Name:       im.Name,
+		Tag:        im.Tag,
+		CreateTime: im.CreateTime,
+		Phase:      im.Phase,
+		Message:    im.Message,
+		Code:       im.Code,
+		Images:     images,
+		Platforms:  append([]PlatformSelector(nil), im.Platforms...),
+	}
+}
+
+// Snapshot returns a deep-copied, point-in-time view of im's state, safe to
+// read or marshal without racing the blob-transfer goroutines that mutate im
+// while a mirror is in flight.
+func (im *ImageMirror) Snapshot() Snapshot {
+	im.mux.Lock()
+	defer im.mux.Unlock()
+	return im.snapshot()
+}
+
+// publish pushes the current state to every subscriber, dropping the update
+// for any subscriber whose buffer is full rather than blocking the mirror.
+func (im *ImageMirror) publish() {
+	im.mux.Lock()
+	snap := im.snapshot()
+	subs := make([]chan Snapshot, 0, len(im.subs))
+	for _, ch := range im.subs {
+		subs = append(subs, ch)
+	}
+	im.mux.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- snap:
+		default:
+		}
 	}
-	return isPending
 }
 
 func (im *ImageMirror) UpdateStatue(code Code, message string) {
+	im.mux.Lock()
 	im.Code = code
 	if message != "" {
 		im.Message = message
+		im.mux.Unlock()
+		im.publish()
 		return
 	}
 	switch code {
@@ -100,24 +401,81 @@ func (im *ImageMirror) UpdateStatue(code Code, message string) {
 	case TagNotFound:
 		im.Message = "tag not found"
 	case Success:
-		im.Message = "mirror successed"
+		if im.Message == "" {
+			im.Message = "mirror successed"
+		} else {
+			im.Message = "mirror successed; " + im.Message
+		}
 		im.Phase = Mirrored
 	}
+	im.mux.Unlock()
+	im.publish()
+}
+
+// NoteSkippedPlatform records that p was skipped because it matched none of
+// im.Platforms, folding it into im.Message so callers polling the status
+// (or the final Success message) can see what was omitted. Skipped platforms
+// are tracked as a set rather than checked via string-containment against
+// the message, so e.g. noting "linux/arm64" and later "linux/arm" doesn't
+// drop the second note just because its string is a substring of the first.
+func (im *ImageMirror) NoteSkippedPlatform(p *v1.Platform) {
+	s := platformString(p)
+	im.mux.Lock()
+	alreadyNoted := false
+	for _, sp := range im.skippedPlatforms {
+		if sp == s {
+			alreadyNoted = true
+			break
+		}
+	}
+	if !alreadyNoted {
+		im.skippedPlatforms = append(im.skippedPlatforms, s)
+		im.Message = "skipped platforms: " + strings.Join(im.skippedPlatforms, ", ")
+	}
+	im.mux.Unlock()
+	im.publish()
+}
+
+// platformString formats p as "os/arch" or, when a variant is set, "os/arch/variant".
+func platformString(p *v1.Platform) string {
+	if p == nil {
+		return "unknown"
+	}
+	if p.Variant != "" {
+		return fmt.Sprintf("%s/%s/%s", p.OS, p.Architecture, p.Variant)
+	}
+	return fmt.Sprintf("%s/%s", p.OS, p.Architecture)
+}
+
+// markRetrying surfaces BlobRetrying so status polling reflects that a blob
+// download failed transiently and is being retried, not that the mirror as a
+// whole has failed.
+func (im *ImageMirror) markRetrying() {
+	im.mux.Lock()
+	im.Code = BlobRetrying
+	im.mux.Unlock()
+	im.publish()
 }
 
 func (im *ImageMirror) UpdateImagePrecent(dig digest.Digest, size int64) error {
+	im.mux.Lock()
 	idx, ok := im.blobToImages[dig]
 	if !ok {
+		im.mux.Unlock()
 		return fmt.Errorf("ImageMirror %s: not found digest %s", im.name, dig)
 	}
 	if idx >= len(im.Images) {
+		im.mux.Unlock()
 		return fmt.Errorf("ImageMirror %s: digest %s found images index lager than len(im.Images)", im.name, dig)
 	}
 	layer, ok := im.Images[idx].Blobs[dig]
 	if !ok {
+		im.mux.Unlock()
 		return fmt.Errorf("ImageMirror %s: not found image layer for digest %s", im.name, dig)
 	}
 	layer.Precent = int(size * 100 / layer.Size)
+	im.mux.Unlock()
+	im.publish()
 	return nil
 }
 
@@ -126,14 +484,18 @@ func (im *ImageMirror) MirrorImage(repo, localrepo distribution.Repository, desc
 		Digest: desc.Digest,
 		Blobs:  make(map[digest.Digest]*Blob),
 	}
-	im.Images = append(im.Images, img)
 	if desc.Platform != nil {
 		img.Architecture = desc.Platform.Architecture
 		img.OS = desc.Platform.OS
 	}
-	wg := sync.WaitGroup{}
+
+	im.mux.Lock()
+	im.Images = append(im.Images, img)
+	imgIdx := len(im.Images) - 1
+	im.mux.Unlock()
+
 	descs := manifest.References()
-	getBlobError := false
+	var missing []distribution.Descriptor
 	for _, d := range descs {
 		switch d.MediaType {
 		case "application/vnd.docker.container.image.v1+json",
@@ -141,28 +503,214 @@ func (im *ImageMirror) MirrorImage(repo, localrepo distribution.Repository, desc
 			"application/vnd.docker.image.rootfs.diff.tar.gzip",
 			"application/vnd.in-toto+json",
 			"application/vnd.oci.image.layer.v1.tar+gzip":
-			img.Blobs[d.Digest] = &Blob{Size: d.Size, Precent: 100}
 			_, err := localrepo.Blobs(context.TODO()).Stat(context.TODO(), d.Digest)
+			blob := &Blob{Size: d.Size, Precent: 100}
+			if err != nil {
+				blob.Precent = 0
+			}
+			im.mux.Lock()
+			img.Blobs[d.Digest] = blob
+			if err != nil {
+				im.blobToImages[d.Digest] = imgIdx
+				if wid, ok := im.resumeWriters[d.Digest]; ok {
+					blob.WriterID = wid
+					delete(im.resumeWriters, d.Digest)
+				}
+			}
+			im.mux.Unlock()
 			if err != nil {
-				im.blobToImages[d.Digest] = len(im.Images) - 1
-				img.Blobs[d.Digest].Precent = 0
-				wg.Add(1)
-				go func(img *Image, dgst digest.Digest) {
-					defer wg.Done()
-					_, err := repo.Blobs(context.TODO()).Get(context.TODO(), dgst)
-					if err != nil {
-						img.Blobs[dgst].Error = err
-						getBlobError = true
-					}
-				}(img, d.Digest)
+				missing = append(missing, d)
 			}
 		default:
 			return fmt.Errorf("unknown mediatype %s", d.MediaType)
 		}
 	}
-	wg.Wait()
-	if getBlobError {
-		return fmt.Errorf("download blob error")
+
+	if len(missing) == 0 {
+		// Every blob this manifest references is already present locally;
+		// skip spawning any transfer workers.
+		return nil
+	}
+
+	g, gctx := errgroup.WithContext(context.Background())
+	for _, d := range missing {
+		dgst, size := d.Digest, d.Size
+		g.Go(func() error {
+			err := im.transferBlob(gctx, repo, localrepo, img, dgst, size)
+			im.mux.Lock()
+			if err != nil {
+				img.Blobs[dgst].Error = err
+			} else {
+				img.Blobs[dgst].Precent = 100
+				img.Blobs[dgst].WriterID = ""
+			}
+			im.mux.Unlock()
+			im.publish()
+			if err != nil {
+				return fmt.Errorf("get blob %s: %s", dgst, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("download blob error: %s", err)
 	}
 	return nil
 }
+
+// transferBlob copies one blob from repo into localrepo, retrying with
+// backoff on failure. Every access to img.Blobs[dgst] goes through im.mux, so
+// it can't race the status/snapshot readers.
+func (im *ImageMirror) transferBlob(ctx context.Context, repo, localrepo distribution.Repository, img *Image, dgst digest.Digest, size int64) error {
+	policy := im.Backoff
+	if policy == (BackoffPolicy{}) {
+		policy = DefaultBlobBackoff
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			im.markRetrying()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(policy.delay(attempt - 1)):
+			}
+		}
+		if err := im.copyBlob(ctx, repo, localrepo, img, dgst, size); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("giving up after %d attempts: %s", policy.MaxAttempts, lastErr)
+}
+
+// copyBlob streams dgst from repo into localrepo, resuming a previous partial
+// upload session (tracked in img.Blobs[dgst].WriterID) instead of
+// re-downloading from the start when one exists.
+func (im *ImageMirror) copyBlob(ctx context.Context, repo, localrepo distribution.Repository, img *Image, dgst digest.Digest, size int64) error {
+	rc, err := repo.Blobs(ctx).Open(ctx, dgst)
+	if err != nil {
+		return fmt.Errorf("open remote blob: %s", err)
+	}
+	defer rc.Close()
+
+	im.mux.Lock()
+	writerID := img.Blobs[dgst].WriterID
+	im.mux.Unlock()
+
+	bw, resumed, err := resumeOrCreateBlobWriter(ctx, localrepo, writerID)
+	if err != nil {
+		return fmt.Errorf("open local blob writer: %s", err)
+	}
+	defer bw.Close()
+
+	im.mux.Lock()
+	img.Blobs[dgst].WriterID = bw.ID()
+	im.mux.Unlock()
+
+	offset := bw.Size()
+	if resumed && offset > 0 {
+		if _, err := rc.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("seek remote blob to resume offset %d: %s", offset, err)
+		}
+	}
+
+	meter := newBlobSpeedMeter(5 * time.Second)
+	progress := &progressWriter{written: offset, onWrite: func(written int64) {
+		im.UpdateImagePrecent(dgst, written)
+		im.mux.Lock()
+		img.Blobs[dgst].Speed = meter.sample(written)
+		im.mux.Unlock()
+	}}
+
+	if _, err := io.Copy(bw, io.TeeReader(rc, progress)); err != nil {
+		return fmt.Errorf("copy blob: %s", err)
+	}
+
+	if _, err := bw.Commit(ctx, distribution.Descriptor{Digest: dgst, Size: size}); err != nil {
+		return fmt.Errorf("commit blob: %s", err)
+	}
+	return nil
+}
+
+// resumeOrCreateBlobWriter resumes the local blob upload session identified
+// by writerID, falling back to a fresh session if writerID is empty or the
+// session has expired.
+func resumeOrCreateBlobWriter(ctx context.Context, localrepo distribution.Repository, writerID string) (bw distribution.BlobWriter, resumed bool, err error) {
+	if writerID != "" {
+		if bw, err := localrepo.Blobs(ctx).Resume(ctx, writerID); err == nil {
+			return bw, true, nil
+		}
+	}
+	bw, err = localrepo.Blobs(ctx).Create(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return bw, false, nil
+}
+
+// progressWriter reports cumulative bytes written as an io.Writer, for use
+// with io.TeeReader alongside an io.Copy.
+type progressWriter struct {
+	written int64
+	onWrite func(total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if w.onWrite != nil {
+		w.onWrite(w.written)
+	}
+	return len(p), nil
+}
+
+// blobSpeedMeter computes a bytes/sec transfer rate over a trailing window.
+type blobSpeedMeter struct {
+	window  time.Duration
+	samples []blobSpeedSample
+}
+
+type blobSpeedSample struct {
+	at    time.Time
+	bytes int64
+}
+
+func newBlobSpeedMeter(window time.Duration) *blobSpeedMeter {
+	return &blobSpeedMeter{window: window}
+}
+
+// sample records the current cumulative byte count and returns the formatted
+// rate over the trailing window.
+func (m *blobSpeedMeter) sample(written int64) string {
+	now := time.Now()
+	m.samples = append(m.samples, blobSpeedSample{at: now, bytes: written})
+
+	cutoff := now.Add(-m.window)
+	i := 0
+	for i < len(m.samples)-1 && m.samples[i].at.Before(cutoff) {
+		i++
+	}
+	m.samples = m.samples[i:]
+
+	first := m.samples[0]
+	elapsed := now.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return "0B/s"
+	}
+	return formatSpeed(float64(written-first.bytes) / elapsed)
+}
+
+func formatSpeed(bytesPerSec float64) string {
+	switch {
+	case bytesPerSec >= 1<<30:
+		return fmt.Sprintf("%.1fGB/s", bytesPerSec/(1<<30))
+	case bytesPerSec >= 1<<20:
+		return fmt.Sprintf("%.1fMB/s", bytesPerSec/(1<<20))
+	case bytesPerSec >= 1<<10:
+		return fmt.Sprintf("%.1fKB/s", bytesPerSec/(1<<10))
+	default:
+		return fmt.Sprintf("%.0fB/s", bytesPerSec)
+	}
+}
@@ -0,0 +1,113 @@
+package v2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/distribution/reference"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestParsePlatformSelector(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    PlatformSelector
+		wantErr bool
+	}{
+		{in: "linux/amd64", want: PlatformSelector{OS: "linux", Architecture: "amd64"}},
+		{in: "linux/arm/v7", want: PlatformSelector{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		{in: "linux", wantErr: true},
+		{in: "linux/arm/v7/extra", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := ParsePlatformSelector(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("ParsePlatformSelector(%q): want error, got %+v", c.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePlatformSelector(%q): %s", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParsePlatformSelector(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestPlatformSelectorMatches(t *testing.T) {
+	amd64 := &v1.Platform{OS: "linux", Architecture: "amd64"}
+	armv7 := &v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}
+
+	cases := []struct {
+		name string
+		sel  PlatformSelector
+		p    *v1.Platform
+		want bool
+	}{
+		{name: "exact match", sel: PlatformSelector{OS: "linux", Architecture: "amd64"}, p: amd64, want: true},
+		{name: "arch mismatch", sel: PlatformSelector{OS: "linux", Architecture: "arm64"}, p: amd64, want: false},
+		{name: "os wildcard", sel: PlatformSelector{Architecture: "amd64"}, p: amd64, want: true},
+		{name: "arch wildcard", sel: PlatformSelector{OS: "linux"}, p: amd64, want: true},
+		{name: "empty selector matches anything", sel: PlatformSelector{}, p: amd64, want: true},
+		{name: "variant required and present", sel: PlatformSelector{OS: "linux", Architecture: "arm", Variant: "v7"}, p: armv7, want: true},
+		{name: "variant required but mismatched", sel: PlatformSelector{OS: "linux", Architecture: "arm", Variant: "v8"}, p: armv7, want: false},
+		{name: "variant not specified ignores platform's variant", sel: PlatformSelector{OS: "linux", Architecture: "arm"}, p: armv7, want: true},
+		{name: "nil platform never matches", sel: PlatformSelector{}, p: nil, want: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.sel.Matches(c.p); got != c.want {
+				t.Errorf("Matches() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMatchesAnyPlatform(t *testing.T) {
+	amd64 := &v1.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := &v1.Platform{OS: "linux", Architecture: "arm64"}
+
+	if !MatchesAnyPlatform(amd64, nil) {
+		t.Error("empty selector list should match every platform")
+	}
+
+	selectors := []PlatformSelector{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm", Variant: "v7"},
+	}
+	if !MatchesAnyPlatform(amd64, selectors) {
+		t.Error("expected amd64 to match one of the selectors")
+	}
+	if MatchesAnyPlatform(arm64, selectors) {
+		t.Error("expected arm64 to match none of the selectors")
+	}
+}
+
+// TestNoteSkippedPlatformDoesNotDropSubstringMatches covers the regression
+// where noting "linux/arm64" and later "linux/arm" dropped the second note,
+// because "linux/arm" is a substring of the already-recorded "linux/arm64"
+// and the old check used strings.Contains against the message.
+func TestNoteSkippedPlatformDoesNotDropSubstringMatches(t *testing.T) {
+	name, err := reference.ParseNormalizedNamed("library/alpine")
+	if err != nil {
+		t.Fatalf("parse name: %s", err)
+	}
+	im := NewImageMirror(name, "latest")
+
+	im.NoteSkippedPlatform(&v1.Platform{OS: "linux", Architecture: "arm64"})
+	im.NoteSkippedPlatform(&v1.Platform{OS: "linux", Architecture: "arm"})
+
+	want := "skipped platforms: linux/arm64, linux/arm"
+	if im.Message != want {
+		t.Errorf("message = %q, want %q", im.Message, want)
+	}
+
+	// Noting the same platform again must not duplicate it.
+	im.NoteSkippedPlatform(&v1.Platform{OS: "linux", Architecture: "arm64"})
+	if n := strings.Count(im.Message, "linux/arm64"); n != 1 {
+		t.Errorf("message %q records linux/arm64 %d times, want 1", im.Message, n)
+	}
+}
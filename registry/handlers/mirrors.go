@@ -4,12 +4,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	dcontext "github.com/docker/distribution/context"
+	v2 "github.com/docker/distribution/registry/api/v2"
 	"github.com/docker/distribution/registry/proxy"
 	"github.com/gorilla/handlers"
 )
 
+// defaultMirrorStreamMinInterval is the default minimum gap between two
+// progress frames written to a streaming client, so a fast-moving mirror
+// doesn't flood slow consumers. Callers can override it with ?min_interval_ms=.
+const defaultMirrorStreamMinInterval = 500 * time.Millisecond
+
 // manifestDispatcher takes the request context and builds the
 // appropriate handler for handling manifest requests.
 func mirrorDispatcher(ctx *Context, r *http.Request) http.Handler {
@@ -21,6 +30,7 @@ func mirrorDispatcher(ctx *Context, r *http.Request) http.Handler {
 
 	mhandler := handlers.MethodHandler{
 		"GET":    http.HandlerFunc(mirrorHandler.GetImageMirror),
+		"PUT":    http.HandlerFunc(mirrorHandler.PutImageMirror),
 		"DELETE": http.HandlerFunc(mirrorHandler.DeleteImageMirror),
 	}
 
@@ -35,17 +45,178 @@ type mirrorHandler struct {
 }
 
 // GetImageMirror fetches the image manifest from the storage backend, if it exists.
+// If the request asks for a stream (?stream=1 or Accept: application/x-ndjson), it
+// instead holds the connection open and writes newline-delimited progress frames.
+// A GET also starts the mirror (if one isn't already running), so ?platform=
+// applies here too.
 func (mh *mirrorHandler) GetImageMirror(w http.ResponseWriter, r *http.Request) {
 	dcontext.GetLogger(mh).Debug("GetImageMirror")
 
-	im := mh.registry.(proxy.ProxyRegistry).MirrorImage(mh.Repository.Named(), mh.Tag)
-	p, _ := json.Marshal(im)
+	platforms, err := platformSelectorsFromQuery(r)
+	if err != nil {
+		respondMirrorBadRequest(w, err)
+		return
+	}
+
+	im := mh.registry.(proxy.ProxyRegistry).MirrorImage(mh.Repository.Named(), mh.Tag, platforms...)
+
+	mh.respondImageMirror(w, r, im)
+}
+
+// PutImageMirror starts (or attaches to) a mirror of the requested tag,
+// optionally scoped to a set of platforms given either as
+// ?platform=os/arch[,os/arch...] or a JSON body {"platforms": ["os/arch", ...]}.
+// It responds the same way GetImageMirror does.
+func (mh *mirrorHandler) PutImageMirror(w http.ResponseWriter, r *http.Request) {
+	dcontext.GetLogger(mh).Debug("PutImageMirror")
+
+	platforms, err := platformSelectorsFromQuery(r)
+	if err != nil {
+		respondMirrorBadRequest(w, err)
+		return
+	}
+	if len(platforms) == 0 {
+		body, err := platformSelectorsFromBody(r)
+		if err != nil {
+			respondMirrorBadRequest(w, err)
+			return
+		}
+		platforms = body
+	}
+
+	im := mh.registry.(proxy.ProxyRegistry).MirrorImage(mh.Repository.Named(), mh.Tag, platforms...)
+
+	mh.respondImageMirror(w, r, im)
+}
+
+// respondImageMirror writes im as the response, streaming progress frames
+// instead if the request asks for it.
+func (mh *mirrorHandler) respondImageMirror(w http.ResponseWriter, r *http.Request, im *v2.ImageMirror) {
+	if isMirrorStreamRequest(r) {
+		mh.streamImageMirror(w, r, im)
+		return
+	}
+
+	p, _ := json.Marshal(im.Snapshot())
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Content-Length", fmt.Sprint(len(p)))
 	w.Write(p)
 }
 
+// platformSelectorsFromQuery parses the comma-separated ?platform= query
+// parameter, if present, into a list of PlatformSelectors.
+func platformSelectorsFromQuery(r *http.Request) ([]v2.PlatformSelector, error) {
+	raw := r.URL.Query().Get("platform")
+	if raw == "" {
+		return nil, nil
+	}
+	var selectors []v2.PlatformSelector
+	for _, s := range strings.Split(raw, ",") {
+		sel, err := v2.ParsePlatformSelector(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+// platformSelectorsFromBody parses a JSON request body of the form
+// {"platforms": ["os/arch", ...]}. A missing or empty body is not an error.
+func platformSelectorsFromBody(r *http.Request) ([]v2.PlatformSelector, error) {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil, nil
+	}
+	var body struct {
+		Platforms []string `json:"platforms"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode mirror request body: %s", err)
+	}
+	var selectors []v2.PlatformSelector
+	for _, s := range body.Platforms {
+		sel, err := v2.ParsePlatformSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+// respondMirrorBadRequest writes a minimal JSON error body for a malformed
+// mirror request.
+func respondMirrorBadRequest(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	p, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+	w.Write(p)
+}
+
+func isMirrorStreamRequest(r *http.Request) bool {
+	if r.URL.Query().Get("stream") != "" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// streamImageMirror writes one JSON status frame per line as im progresses,
+// coalescing updates that arrive faster than minInterval and stopping once the
+// mirror reaches a terminal phase, the subscription is closed, or the request
+// context is cancelled.
+func (mh *mirrorHandler) streamImageMirror(w http.ResponseWriter, r *http.Request, im *v2.ImageMirror) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		p, _ := json.Marshal(im.Snapshot())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", fmt.Sprint(len(p)))
+		w.Write(p)
+		return
+	}
+
+	minInterval := defaultMirrorStreamMinInterval
+	if ms, err := strconv.Atoi(r.URL.Query().Get("min_interval_ms")); err == nil && ms >= 0 {
+		minInterval = time.Duration(ms) * time.Millisecond
+	}
+
+	ch, unsubscribe := im.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	var last time.Time
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case snap, ok := <-ch:
+			if !ok {
+				return
+			}
+			// BlobRetrying means a blob is being retried after a transient
+			// failure, not that the mirror has failed; keep streaming.
+			terminal := snap.Phase == v2.Mirrored ||
+				(snap.Code != 0 && snap.Code != v2.Success && snap.Code != v2.BlobRetrying)
+			if !terminal && time.Since(last) < minInterval {
+				continue
+			}
+			last = time.Now()
+			if err := enc.Encode(snap); err != nil {
+				return
+			}
+			flusher.Flush()
+			if terminal {
+				return
+			}
+		}
+	}
+}
+
 // GetImageMirror fetches the image manifest from the storage backend, if it exists.
 func (mh *mirrorHandler) DeleteImageMirror(w http.ResponseWriter, r *http.Request) {
 	dcontext.GetLogger(mh).Debug("DeleteImageMirror")
@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+
+	"github.com/docker/distribution"
+	"github.com/opencontainers/go-digest"
+)
+
+// maxManifestCacheEntries bounds the manifest cache to a fixed number of
+// entries, evicting the oldest one once full, so a registry mirroring many
+// distinct digests doesn't grow the cache without bound.
+const maxManifestCacheEntries = 1024
+
+// ManifestCache is a small content-addressable store of raw manifest bytes,
+// keyed by digest, consulted before fetching a manifest from upstream. Unlike
+// MirrorStore it is not persisted: a cache miss after a restart just costs
+// one extra round trip to the upstream source.
+type ManifestCache interface {
+	Get(dgst digest.Digest) (mediaType string, payload []byte, ok bool)
+	Put(dgst digest.Digest, mediaType string, payload []byte)
+}
+
+// memoryManifestCache is a fixed-size, FIFO-evicted, in-memory ManifestCache.
+type memoryManifestCache struct {
+	mux      sync.Mutex
+	byDigest map[digest.Digest]manifestCacheEntry
+	order    []digest.Digest
+}
+
+type manifestCacheEntry struct {
+	mediaType string
+	payload   []byte
+}
+
+// newMemoryManifestCache returns an empty in-memory ManifestCache.
+func newMemoryManifestCache() *memoryManifestCache {
+	return &memoryManifestCache{}
+}
+
+func (c *memoryManifestCache) Get(dgst digest.Digest) (string, []byte, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	e, ok := c.byDigest[dgst]
+	if !ok {
+		return "", nil, false
+	}
+	return e.mediaType, e.payload, true
+}
+
+func (c *memoryManifestCache) Put(dgst digest.Digest, mediaType string, payload []byte) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if _, ok := c.byDigest[dgst]; ok {
+		return
+	}
+	if c.byDigest == nil {
+		c.byDigest = make(map[digest.Digest]manifestCacheEntry)
+	}
+	if len(c.order) >= maxManifestCacheEntries {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.byDigest, oldest)
+	}
+	c.byDigest[dgst] = manifestCacheEntry{mediaType: mediaType, payload: append([]byte(nil), payload...)}
+	c.order = append(c.order, dgst)
+}
+
+// getManifestCached returns the manifest for dgst, consulting cache first
+// (verifying the cached payload still hashes to dgst before trusting it) and
+// falling back to ms.Get on a miss, writing the fetched manifest through to
+// cache once its digest is verified.
+func getManifestCached(ctx context.Context, cache ManifestCache, ms distribution.ManifestService, dgst digest.Digest) (distribution.Manifest, error) {
+	if cache != nil {
+		if mediaType, payload, ok := cache.Get(dgst); ok && digest.FromBytes(payload) == dgst {
+			if m, _, err := distribution.UnmarshalManifest(mediaType, payload); err == nil {
+				return m, nil
+			}
+		}
+	}
+
+	m, err := ms.Get(ctx, dgst)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		if mediaType, payload, err := m.Payload(); err == nil && digest.FromBytes(payload) == dgst {
+			cache.Put(dgst, mediaType, payload)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/opencontainers/go-digest"
+)
+
+func TestMemoryManifestCacheGetPut(t *testing.T) {
+	c := newMemoryManifestCache()
+
+	if _, _, ok := c.Get("sha256:missing"); ok {
+		t.Fatal("Get on an empty cache returned ok=true")
+	}
+
+	payload := []byte(`{"schemaVersion":2}`)
+	dgst := digest.FromBytes(payload)
+	c.Put(dgst, "application/vnd.oci.image.manifest.v1+json", payload)
+
+	mediaType, got, ok := c.Get(dgst)
+	if !ok {
+		t.Fatal("Get after Put returned ok=false")
+	}
+	if mediaType != "application/vnd.oci.image.manifest.v1+json" {
+		t.Errorf("mediaType = %q, want %q", mediaType, "application/vnd.oci.image.manifest.v1+json")
+	}
+	if string(got) != string(payload) {
+		t.Errorf("payload = %q, want %q", got, payload)
+	}
+}
+
+// TestMemoryManifestCachePutIsImmutable ensures the cache copies the payload
+// on Put, so a caller mutating its own buffer afterwards can't corrupt the
+// cached entry.
+func TestMemoryManifestCachePutIsImmutable(t *testing.T) {
+	c := newMemoryManifestCache()
+	payload := []byte("original")
+	dgst := digest.FromBytes(payload)
+	c.Put(dgst, "text/plain", payload)
+
+	payload[0] = 'X'
+
+	_, got, _ := c.Get(dgst)
+	if string(got) != "original" {
+		t.Errorf("cached payload changed to %q after mutating the caller's buffer", got)
+	}
+}
+
+// TestMemoryManifestCachePutKeepsFirstEntry covers that Put does not
+// overwrite an existing entry for the same digest (a digest is
+// content-addressed, so the second write is always equivalent to the first).
+func TestMemoryManifestCachePutKeepsFirstEntry(t *testing.T) {
+	c := newMemoryManifestCache()
+	payload := []byte("v1")
+	dgst := digest.FromBytes(payload)
+	c.Put(dgst, "text/plain", payload)
+	c.Put(dgst, "application/json", []byte("different"))
+
+	mediaType, got, ok := c.Get(dgst)
+	if !ok {
+		t.Fatal("Get returned ok=false")
+	}
+	if mediaType != "text/plain" || string(got) != "v1" {
+		t.Errorf("Get = (%q, %q), want (%q, %q)", mediaType, got, "text/plain", "v1")
+	}
+}
+
+// TestMemoryManifestCacheEvictsOldestOnceFull covers the FIFO eviction policy:
+// once maxManifestCacheEntries is reached, the oldest entry is dropped to
+// make room for a new one.
+func TestMemoryManifestCacheEvictsOldestOnceFull(t *testing.T) {
+	c := newMemoryManifestCache()
+	var digests []digest.Digest
+	for i := 0; i < maxManifestCacheEntries; i++ {
+		payload := []byte{byte(i), byte(i >> 8)}
+		dgst := digest.FromBytes(payload)
+		digests = append(digests, dgst)
+		c.Put(dgst, "text/plain", payload)
+	}
+
+	overflow := []byte("one more than the cache can hold")
+	overflowDigest := digest.FromBytes(overflow)
+	c.Put(overflowDigest, "text/plain", overflow)
+
+	if _, _, ok := c.Get(digests[0]); ok {
+		t.Error("oldest entry was not evicted once the cache reached capacity")
+	}
+	if _, _, ok := c.Get(digests[1]); !ok {
+		t.Error("second-oldest entry was evicted; only the oldest should have been")
+	}
+	if _, _, ok := c.Get(overflowDigest); !ok {
+		t.Error("the entry that triggered eviction was not itself cached")
+	}
+}
+
+// fakeManifest is a minimal distribution.Manifest backed by a fixed payload.
+type fakeManifest struct {
+	mediaType string
+	payload   []byte
+}
+
+func (m *fakeManifest) References() []distribution.Descriptor { return nil }
+
+func (m *fakeManifest) Payload() (string, []byte, error) {
+	return m.mediaType, m.payload, nil
+}
+
+// fakeManifestService is a distribution.ManifestService stub that counts
+// Get calls and always returns the same manifest, so tests can assert
+// getManifestCached did (or didn't) fall through to the upstream service.
+type fakeManifestService struct {
+	manifest distribution.Manifest
+	gets     int
+}
+
+func (s *fakeManifestService) Exists(ctx context.Context, dgst digest.Digest) (bool, error) {
+	return true, nil
+}
+
+func (s *fakeManifestService) Get(ctx context.Context, dgst digest.Digest, options ...distribution.ManifestServiceOption) (distribution.Manifest, error) {
+	s.gets++
+	return s.manifest, nil
+}
+
+func (s *fakeManifestService) Put(ctx context.Context, manifest distribution.Manifest, options ...distribution.ManifestServiceOption) (digest.Digest, error) {
+	return "", nil
+}
+
+func (s *fakeManifestService) Delete(ctx context.Context, dgst digest.Digest) error {
+	return nil
+}
+
+func TestGetManifestCachedFetchesOnceThenHitsCache(t *testing.T) {
+	payload := []byte(`{"schemaVersion":2}`)
+	dgst := digest.FromBytes(payload)
+	mediaType := "application/vnd.oci.image.manifest.v1+json"
+	ms := &fakeManifestService{manifest: &fakeManifest{mediaType: mediaType, payload: payload}}
+	cache := newMemoryManifestCache()
+
+	m1, err := getManifestCached(context.Background(), cache, ms, dgst)
+	if err != nil {
+		t.Fatalf("getManifestCached: %s", err)
+	}
+	if m1 == nil {
+		t.Fatal("getManifestCached returned a nil manifest")
+	}
+	if ms.gets != 1 {
+		t.Fatalf("ManifestService.Get called %d times on a cache miss, want 1", ms.gets)
+	}
+
+	m2, err := getManifestCached(context.Background(), cache, ms, dgst)
+	if err != nil {
+		t.Fatalf("getManifestCached: %s", err)
+	}
+	if m2 == nil {
+		t.Fatal("getManifestCached returned a nil manifest")
+	}
+	if ms.gets != 1 {
+		t.Fatalf("ManifestService.Get called %d times, want 1 (second call should hit the cache)", ms.gets)
+	}
+}
+
+// TestGetManifestCachedIgnoresTamperedEntry covers that a cached payload
+// which no longer hashes to the digest it was stored under (corruption, or a
+// deliberate mismatch) is not trusted: getManifestCached must fall through to
+// the upstream ManifestService instead.
+func TestGetManifestCachedIgnoresTamperedEntry(t *testing.T) {
+	payload := []byte(`{"schemaVersion":2}`)
+	dgst := digest.FromBytes(payload)
+	cache := newMemoryManifestCache()
+	cache.Put(dgst, "application/vnd.oci.image.manifest.v1+json", []byte("tampered"))
+
+	ms := &fakeManifestService{manifest: &fakeManifest{
+		mediaType: "application/vnd.oci.image.manifest.v1+json",
+		payload:   payload,
+	}}
+
+	if _, err := getManifestCached(context.Background(), cache, ms, dgst); err != nil {
+		t.Fatalf("getManifestCached: %s", err)
+	}
+	if ms.gets != 1 {
+		t.Fatalf("ManifestService.Get called %d times, want 1 (tampered cache entry must not be trusted)", ms.gets)
+	}
+}
+
+func TestGetManifestCachedWithNilCache(t *testing.T) {
+	payload := []byte(`{"schemaVersion":2}`)
+	dgst := digest.FromBytes(payload)
+	ms := &fakeManifestService{manifest: &fakeManifest{
+		mediaType: "application/vnd.oci.image.manifest.v1+json",
+		payload:   payload,
+	}}
+
+	if _, err := getManifestCached(context.Background(), nil, ms, dgst); err != nil {
+		t.Fatalf("getManifestCached with nil cache: %s", err)
+	}
+	if ms.gets != 1 {
+		t.Fatalf("ManifestService.Get called %d times, want 1", ms.gets)
+	}
+}
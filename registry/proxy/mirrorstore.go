@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	dcontext "github.com/docker/distribution/context"
+
+	"github.com/docker/distribution/configuration"
+	v2 "github.com/docker/distribution/registry/api/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+// errMirrorRecordNotFound is returned by a MirrorStore when no record exists for a ref.
+var errMirrorRecordNotFound = fmt.Errorf("mirror record not found")
+
+// MirrorStore persists ImageMirror records so in-flight and historical mirror
+// state survives a registry restart. Records are keyed by "name:tag", the
+// same key MirrorController uses for its in-memory map.
+type MirrorStore interface {
+	Get(ref string) (*v2.ImageMirror, error)
+	Put(ref string, im *v2.ImageMirror) error
+	Delete(ref string) error
+	List() ([]*v2.ImageMirror, error)
+}
+
+// newMirrorStore builds the MirrorStore configured for this controller. With
+// no directory configured, mirror state is not persisted across restarts.
+func newMirrorStore(cfg configuration.Mirror) (MirrorStore, error) {
+	if cfg.StoreDir == "" {
+		return nil, nil
+	}
+	switch cfg.StoreDriver {
+	case "", "bolt":
+		return NewBoltMirrorStore(filepath.Join(cfg.StoreDir, "mirrors.db"))
+	case "filesystem":
+		return NewFSMirrorStore(cfg.StoreDir)
+	default:
+		return nil, fmt.Errorf("unknown mirror store driver %q", cfg.StoreDriver)
+	}
+}
+
+var mirrorStoreBucket = []byte("mirrors")
+
+// boltMirrorStore stores mirror records as JSON values in a single BoltDB bucket.
+type boltMirrorStore struct {
+	db *bolt.DB
+}
+
+// NewBoltMirrorStore opens (creating if needed) a BoltDB-backed MirrorStore at path.
+func NewBoltMirrorStore(path string) (MirrorStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("create mirror store dir: %s", err)
+	}
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open mirror store: %s", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(mirrorStoreBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("init mirror store bucket: %s", err)
+	}
+	return &boltMirrorStore{db: db}, nil
+}
+
+func (s *boltMirrorStore) Get(ref string) (*v2.ImageMirror, error) {
+	var im *v2.ImageMirror
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(mirrorStoreBucket).Get([]byte(ref))
+		if v == nil {
+			return errMirrorRecordNotFound
+		}
+		im = new(v2.ImageMirror)
+		return json.Unmarshal(v, im)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return im, nil
+}
+
+func (s *boltMirrorStore) Put(ref string, im *v2.ImageMirror) error {
+	p, err := json.Marshal(im)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(mirrorStoreBucket).Put([]byte(ref), p)
+	})
+}
+
+func (s *boltMirrorStore) Delete(ref string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(mirrorStoreBucket).Delete([]byte(ref))
+	})
+}
+
+// List returns every persisted record. A record that fails to unmarshal is
+// logged and skipped rather than aborting the whole call, so one corrupt
+// entry can't hide every other mirror from startup reconciliation.
+func (s *boltMirrorStore) List() ([]*v2.ImageMirror, error) {
+	var all []*v2.ImageMirror
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(mirrorStoreBucket).ForEach(func(k, v []byte) error {
+			im := new(v2.ImageMirror)
+			if err := json.Unmarshal(v, im); err != nil {
+				dcontext.GetLogger(context.Background()).Errorf("mirror store: skip corrupt record %s: %s", k, err)
+				return nil
+			}
+			all = append(all, im)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// fsMirrorStore stores each mirror record as its own JSON file, named by the
+// sha256 of its ref so repository names containing "/" don't need escaping.
+type fsMirrorStore struct {
+	dir string
+	mux sync.Mutex
+}
+
+// NewFSMirrorStore opens (creating if needed) a directory of JSON files as a MirrorStore.
+func NewFSMirrorStore(dir string) (MirrorStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create mirror store dir: %s", err)
+	}
+	return &fsMirrorStore{dir: dir}, nil
+}
+
+func (s *fsMirrorStore) path(ref string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(ref))))
+}
+
+func (s *fsMirrorStore) Get(ref string) (*v2.ImageMirror, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	b, err := os.ReadFile(s.path(ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errMirrorRecordNotFound
+		}
+		return nil, err
+	}
+	im := new(v2.ImageMirror)
+	if err := json.Unmarshal(b, im); err != nil {
+		return nil, err
+	}
+	return im, nil
+}
+
+func (s *fsMirrorStore) Put(ref string, im *v2.ImageMirror) error {
+	p, err := json.Marshal(im)
+	if err != nil {
+		return err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return os.WriteFile(s.path(ref), p, 0600)
+}
+
+func (s *fsMirrorStore) Delete(ref string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	err := os.Remove(s.path(ref))
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// List returns every persisted record. A file that can't be read or
+// unmarshaled is logged and skipped rather than aborting the whole call, so
+// one corrupt entry can't hide every other mirror from startup reconciliation.
+func (s *fsMirrorStore) List() ([]*v2.ImageMirror, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	all := make([]*v2.ImageMirror, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			dcontext.GetLogger(context.Background()).Errorf("mirror store: read %s: %s", e.Name(), err)
+			continue
+		}
+		im := new(v2.ImageMirror)
+		if err := json.Unmarshal(b, im); err != nil {
+			dcontext.GetLogger(context.Background()).Errorf("mirror store: skip corrupt record %s: %s", e.Name(), err)
+			continue
+		}
+		all = append(all, im)
+	}
+	return all, nil
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/distribution/reference"
+	v2 "github.com/docker/distribution/registry/api/v2"
+	"github.com/opencontainers/go-digest"
+)
+
+func newTestImageMirror(t *testing.T, blobErr error) *v2.ImageMirror {
+	t.Helper()
+	name, err := reference.ParseNormalizedNamed("library/alpine")
+	if err != nil {
+		t.Fatalf("parse name: %s", err)
+	}
+	im := v2.NewImageMirror(name, "latest")
+	im.Images = []*v2.Image{
+		{
+			Digest: "sha256:aaaa",
+			Blobs: map[digest.Digest]*v2.Blob{
+				"sha256:bbbb": {Size: 10, Error: blobErr},
+			},
+		},
+	}
+	return im
+}
+
+// TestFSMirrorStoreRoundTripsBlobError covers the case a plain json.Marshal
+// of a Blob.Error gets wrong: a non-nil error must survive Put then Get, not
+// turn into "{}" and fail to unmarshal back into the error-typed field.
+func TestFSMirrorStoreRoundTripsBlobError(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFSMirrorStore(dir)
+	if err != nil {
+		t.Fatalf("NewFSMirrorStore: %s", err)
+	}
+
+	im := newTestImageMirror(t, errors.New("get blob: connection reset"))
+	if err := store.Put("library/alpine:latest", im); err != nil {
+		t.Fatalf("Put: %s", err)
+	}
+
+	got, err := store.Get("library/alpine:latest")
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	blob := got.Images[0].Blobs["sha256:bbbb"]
+	if blob.Error == nil || blob.Error.Error() != "get blob: connection reset" {
+		t.Fatalf("blob error = %v, want %q", blob.Error, "get blob: connection reset")
+	}
+}
+
+// TestFSMirrorStoreListSkipsCorruptRecord ensures one unreadable/corrupt
+// record doesn't hide every other persisted mirror from List(), since List()
+// backs startup reconciliation.
+func TestFSMirrorStoreListSkipsCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFSMirrorStore(dir)
+	if err != nil {
+		t.Fatalf("NewFSMirrorStore: %s", err)
+	}
+
+	good := newTestImageMirror(t, nil)
+	if err := store.Put("library/alpine:latest", good); err != nil {
+		t.Fatalf("Put good: %s", err)
+	}
+
+	badPath := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(badPath, []byte("not json"), 0600); err != nil {
+		t.Fatalf("write corrupt record: %s", err)
+	}
+
+	all, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %s", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("List returned %d records, want 1 (corrupt record should be skipped)", len(all))
+	}
+	if all[0].Name != "docker.io/library/alpine" {
+		t.Fatalf("List()[0].Name = %q, want %q", all[0].Name, "docker.io/library/alpine")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -22,18 +23,32 @@ import (
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
-var once sync.Once
-
 type ProxyRegistry interface {
-	MirrorImage(name reference.Named, tag string) *v2.ImageMirror
+	MirrorImage(name reference.Named, tag string, platforms ...v2.PlatformSelector) *v2.ImageMirror
 	DeleteMirrorImage(name reference.Named, tag string)
 }
 
 type MirrorController struct {
-	ctx            context.Context
-	mirrors        map[string]*v2.ImageMirror
-	url            url.URL
-	authChallenger authChallenger
+	ctx     context.Context
+	mirrors map[string]*v2.ImageMirror
+	store   MirrorStore
+
+	// authChallengers caches one authChallenger per configured source, keyed
+	// by configuration.MirrorSource.Name, so each upstream keeps its own
+	// credentials for the life of the process instead of sharing a single
+	// sync.Once-initialized challenger across every source.
+	authChallengers map[string]authChallenger
+	challengerMux   sync.Mutex
+
+	// pool holds one entry per ref with a mirror worker currently running,
+	// so a second concurrent request for the same ref attaches to the
+	// existing *ImageMirror instead of spawning a duplicate worker.
+	pool map[string]chan struct{}
+
+	// manifestCache avoids re-fetching a manifest from upstream when an
+	// identical digest was already mirrored, regardless of which tag or
+	// repository referenced it.
+	manifestCache ManifestCache
 
 	embedded distribution.Namespace
 
@@ -43,26 +58,81 @@ type MirrorController struct {
 }
 
 func NewMirrorController(ctx context.Context, config *configuration.Configuration, embedded distribution.Namespace) (ProxyRegistry, error) {
-	parts := []string{}
-	if config.HTTP.Addr == "" {
-		parts = []string{"127.0.0.1", "5000"}
-	} else {
-		parts = strings.Split(config.HTTP.Addr, ":")
-		if parts[0] == "" {
-			parts[0] = "127.0.0.1"
-		}
+	store, err := newMirrorStore(config.Mirror)
+	if err != nil {
+		return nil, fmt.Errorf("init mirror store: %s", err)
 	}
-	url, _ := url.Parse(fmt.Sprintf("http://%s:%s", parts[0], parts[1]))
 	c := &MirrorController{
-		ctx:      ctx,
-		mirrors:  make(map[string]*v2.ImageMirror),
-		url:      *url,
-		embedded: embedded,
+		ctx:             ctx,
+		mirrors:         make(map[string]*v2.ImageMirror),
+		pool:            make(map[string]chan struct{}),
+		authChallengers: make(map[string]authChallenger),
+		manifestCache:   newMemoryManifestCache(),
+		embedded:        embedded,
+		store:           store,
+		Mirror:          config.Mirror,
 	}
+	c.reconcile()
 	go c.gc()
 	return c, nil
 }
 
+// reconcile loads every record from the store, hydrates it, and resolves any
+// that were left in Mirroring by a registry that restarted mid-mirror: if the
+// local repository already has every referenced blob the record is marked
+// Mirrored, otherwise it is reset to Pending and re-enqueued.
+func (c *MirrorController) reconcile() {
+	if c.store == nil {
+		return
+	}
+	records, err := c.store.List()
+	if err != nil {
+		dcontext.GetLogger(c.ctx).Errorf("mirror store: list records: %s", err)
+		return
+	}
+	for _, im := range records {
+		if err := im.Hydrate(); err != nil {
+			dcontext.GetLogger(c.ctx).Errorf("mirror store: %s", err)
+			continue
+		}
+		ref := im.Name + ":" + im.Tag
+		if im.Phase == v2.Mirroring {
+			if c.hasAllBlobs(im) {
+				im.UpdateStatue(v2.Success, "")
+			} else {
+				im.ResetToPending()
+			}
+			if err := c.store.Put(ref, im); err != nil {
+				dcontext.GetLogger(c.ctx).Errorf("mirror store: put %s: %s", ref, err)
+			}
+		}
+		c.mirrors[ref] = im
+		if im.Phase == v2.Pending {
+			im.Phase = v2.Mirroring
+			c.pool[ref] = make(chan struct{})
+			go c.mirrorimages(im)
+		}
+	}
+}
+
+// hasAllBlobs reports whether every blob referenced by im is already present
+// in the local (embedded) repository.
+func (c *MirrorController) hasAllBlobs(im *v2.ImageMirror) bool {
+	localrepo, err := c.embedded.Repository(c.ctx, im.Named())
+	if err != nil {
+		return false
+	}
+	bs := localrepo.Blobs(c.ctx)
+	for _, img := range im.Images {
+		for dgst := range img.Blobs {
+			if _, err := bs.Stat(c.ctx, dgst); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (c *MirrorController) gc() {
 	ticker := time.NewTicker(5 * time.Minute)
 	for {
@@ -78,6 +148,11 @@ func (c *MirrorController) gc() {
 			}
 			for _, ref := range needDeleted {
 				delete(c.mirrors, ref)
+				if c.store != nil {
+					if err := c.store.Delete(ref); err != nil {
+						dcontext.GetLogger(c.ctx).Errorf("mirror store: delete %s: %s", ref, err)
+					}
+				}
 			}
 			c.mux.Unlock()
 		case <-c.ctx.Done():
@@ -86,27 +161,109 @@ func (c *MirrorController) gc() {
 	}
 }
 
-func (c *MirrorController) configAuth() error {
-	var authErr error
-	once.Do(func() {
-		cs, err := configureAuth(c.Username, c.Password, c.url.String())
-		if err != nil {
-			authErr = err
-			return
+// resolveSource picks the upstream source configured for name, matching the
+// longest configured RepositoryPrefix, and returns the (possibly rewritten,
+// per RewritePrefix) repository name to request against that source.
+func (c *MirrorController) resolveSource(name reference.Named) (*configuration.MirrorSource, reference.Named, error) {
+	var best *configuration.MirrorSource
+	for i := range c.Mirror.Sources {
+		src := &c.Mirror.Sources[i]
+		if src.RepositoryPrefix != "" && !strings.HasPrefix(name.Name(), src.RepositoryPrefix) {
+			continue
 		}
-		c.authChallenger = &remoteAuthChallenger{
-			remoteURL: c.url,
-			cm:        challenge.NewSimpleManager(),
-			cs:        cs,
+		if best == nil || len(src.RepositoryPrefix) > len(best.RepositoryPrefix) {
+			best = src
 		}
-	})
-	c.authChallenger.tryEstablishChallenges(context.TODO())
-	return authErr
+	}
+	if best == nil {
+		return nil, nil, fmt.Errorf("no mirror source configured for %s", name.Name())
+	}
+
+	upstreamName := name
+	if best.RepositoryPrefix != "" && best.RewritePrefix != "" {
+		rewritten := best.RewritePrefix + strings.TrimPrefix(name.Name(), best.RepositoryPrefix)
+		n, err := reference.WithName(rewritten)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rewrite repository name %s: %s", name.Name(), err)
+		}
+		upstreamName = n
+	}
+	return best, upstreamName, nil
 }
 
-func (c *MirrorController) MirrorImage(name reference.Named, tag string) *v2.ImageMirror {
-	im := c.getim(name, tag)
-	if im.IsPending() {
+// authChallengerFor returns the cached authChallenger for src, building and
+// caching one on first use. Each source keeps its own credential store for
+// the life of the process, fixing the previous sync.Once that bound a single
+// credential set process-wide regardless of which source was being mirrored.
+func (c *MirrorController) authChallengerFor(src *configuration.MirrorSource) (authChallenger, error) {
+	c.challengerMux.Lock()
+	a, ok := c.authChallengers[src.Name]
+	c.challengerMux.Unlock()
+	if ok {
+		a.tryEstablishChallenges(context.TODO())
+		return a, nil
+	}
+
+	cs, err := configureAuth(src.Username, src.Password, src.Host)
+	if err != nil {
+		return nil, fmt.Errorf("configure auth for source %s: %s", src.Name, err)
+	}
+	remoteURL, err := url.Parse(src.Host)
+	if err != nil {
+		return nil, fmt.Errorf("parse source %s host: %s", src.Name, err)
+	}
+	a = &remoteAuthChallenger{
+		remoteURL: *remoteURL,
+		cm:        challenge.NewSimpleManager(),
+		cs:        cs,
+	}
+
+	c.challengerMux.Lock()
+	if existing, ok := c.authChallengers[src.Name]; ok {
+		c.challengerMux.Unlock()
+		existing.tryEstablishChallenges(context.TODO())
+		return existing, nil
+	}
+	c.authChallengers[src.Name] = a
+	c.challengerMux.Unlock()
+
+	a.tryEstablishChallenges(context.TODO())
+	return a, nil
+}
+
+// MirrorImage returns the ImageMirror for name:tag, starting a mirror worker
+// for it if one isn't already running. The lookup, the Pending->Mirroring
+// flip, and the pool registration all happen under a single lock so that two
+// concurrent callers for the same ref can never both start a worker; the
+// second caller instead gets back the same *ImageMirror the first is driving.
+//
+// platforms, if non-empty, restricts an image-index mirror to the matching
+// platforms; it only takes effect when a new record is created; a request
+// that attaches to an already-running or completed mirror keeps whatever
+// selection that mirror started with. With no platforms given, the
+// configured c.Mirror.AllPlatforms default decides whether the index is
+// mirrored in full or restricted to the host platform.
+func (c *MirrorController) MirrorImage(name reference.Named, tag string, platforms ...v2.PlatformSelector) *v2.ImageMirror {
+	ref := name.Name() + ":" + tag
+
+	if len(platforms) == 0 && !c.Mirror.AllPlatforms {
+		platforms = []v2.PlatformSelector{{OS: runtime.GOOS, Architecture: runtime.GOARCH}}
+	}
+
+	c.mux.Lock()
+	im, isNew := c.getimLocked(ref, name, tag, platforms)
+	_, inflight := c.pool[ref]
+	shouldStart := !inflight && im.Phase == v2.Pending
+	if shouldStart {
+		im.Phase = v2.Mirroring
+		c.pool[ref] = make(chan struct{})
+	}
+	if isNew {
+		c.persist(ref, im)
+	}
+	c.mux.Unlock()
+
+	if shouldStart {
 		go c.mirrorimages(im)
 	}
 	return im
@@ -117,51 +274,114 @@ func (c *MirrorController) DeleteMirrorImage(name reference.Named, tag string) {
 	c.delim(ref)
 }
 
-func (c *MirrorController) getim(name reference.Named, tag string) *v2.ImageMirror {
-	ref := name.Name() + ":" + tag
-	c.mux.RLock()
-	im, ok := c.mirrors[ref]
-	c.mux.RUnlock()
-	if !ok {
-		im = v2.NewImageMirror(name, tag)
-		c.mux.Lock()
-		if _, ok := c.mirrors[ref]; !ok {
-			c.mirrors[ref] = im
-		} else {
-			im = c.mirrors[ref]
+// getimLocked looks up (or creates, consulting the store first) the
+// ImageMirror for ref. Callers must hold c.mux. The second return value
+// reports whether a brand new record was created; platforms only applies
+// to that new-record case.
+func (c *MirrorController) getimLocked(ref string, name reference.Named, tag string, platforms []v2.PlatformSelector) (*v2.ImageMirror, bool) {
+	if im, ok := c.mirrors[ref]; ok {
+		return im, false
+	}
+	if c.store != nil {
+		if stored, err := c.store.Get(ref); err == nil {
+			if err := stored.Hydrate(); err == nil {
+				c.mirrors[ref] = stored
+				return stored, false
+			}
 		}
-		c.mux.Unlock()
 	}
-	return im
+	im := v2.NewImageMirror(name, tag, platforms...)
+	c.mirrors[ref] = im
+	return im, true
 }
 
+// delim deletes the record for ref. If a worker is currently mirroring ref,
+// it waits for that worker to finish (and release its pool entry) first, so
+// a caller that immediately re-requests the same ref afterwards is not
+// handed a new record that MirrorImage's shouldStart check refuses to start
+// because the old, now-stale, pool entry is still present.
 func (c *MirrorController) delim(ref string) {
+	c.mux.Lock()
+	done, inflight := c.pool[ref]
+	c.mux.Unlock()
+	if inflight {
+		<-done
+	}
+
 	c.mux.Lock()
 	defer c.mux.Unlock()
 	delete(c.mirrors, ref)
+	if c.store != nil {
+		if err := c.store.Delete(ref); err != nil {
+			dcontext.GetLogger(c.ctx).Errorf("mirror store: delete %s: %s", ref, err)
+		}
+	}
+}
+
+// persist writes im to the store, if one is configured. Failures are logged,
+// not returned, since the in-memory record remains authoritative for the
+// lifetime of this process.
+func (c *MirrorController) persist(ref string, im *v2.ImageMirror) {
+	if c.store == nil {
+		return
+	}
+	if err := c.store.Put(ref, im); err != nil {
+		dcontext.GetLogger(c.ctx).Errorf("mirror store: put %s: %s", ref, err)
+	}
+}
+
+// finishMirroring persists the final state and releases ref's pool entry,
+// unblocking any caller waiting to start a fresh mirror of the same ref
+// (e.g. after DeleteMirrorImage) and signalling anyone holding the channel
+// that this worker is done.
+func (c *MirrorController) finishMirroring(ref string, im *v2.ImageMirror) {
+	c.persist(ref, im)
+	c.mux.Lock()
+	if done, ok := c.pool[ref]; ok {
+		delete(c.pool, ref)
+		close(done)
+	}
+	c.mux.Unlock()
 }
 
 func (c *MirrorController) mirrorimages(im *v2.ImageMirror) {
-	c.configAuth()
-	a := c.authChallenger
+	ref := im.Name + ":" + im.Tag
+	defer c.finishMirroring(ref, im)
+
+	src, upstreamName, err := c.resolveSource(im.Named())
+	if err != nil {
+		im.UpdateStatue(v2.NameInvalid, fmt.Sprintf("resolve mirror source: %s", err))
+		return
+	}
+
+	a, err := c.authChallengerFor(src)
+	if err != nil {
+		im.UpdateStatue(v2.Unknown, fmt.Sprintf("configure auth: %s", err))
+		return
+	}
+
+	baseTransport := http.DefaultTransport
+	if src.TLS != nil {
+		baseTransport = &http.Transport{TLSClientConfig: src.TLS}
+	}
 
 	tkopts := auth.TokenHandlerOptions{
-		Transport:   http.DefaultTransport,
+		Transport:   baseTransport,
 		Credentials: a.credentialStore(),
 		Scopes: []auth.Scope{
 			auth.RepositoryScope{
-				Repository: im.Name,
+				Repository: upstreamName.Name(),
 				Actions:    []string{"pull"},
 			},
 		},
 		Logger: dcontext.GetLogger(c.ctx),
 	}
 
-	tr := transport.NewTransport(http.DefaultTransport,
+	tr := transport.NewTransport(baseTransport,
 		auth.NewAuthorizer(a.challengeManager(),
 			auth.NewTokenHandlerWithOptions(tkopts)))
 
-	repo, err := client.NewRepository(im.Named(), c.url.String(), tr, im)
+	repo, err := client.NewRepository(upstreamName, src.Host, tr, im)
 	if err != nil {
 		im.UpdateStatue(v2.NameInvalid, fmt.Sprintf("get registry repository error: %s", err))
 		return
@@ -207,10 +427,15 @@ func (c *MirrorController) mirrorimages(im *v2.ImageMirror) {
 			d.Platform = &v1.Platform{
 				Architecture: md.Platform.Architecture,
 				OS:           md.Platform.OS,
+				Variant:      md.Platform.Variant,
+			}
+			if !v2.MatchesAnyPlatform(d.Platform, im.Platforms) {
+				im.NoteSkippedPlatform(d.Platform)
+				continue
 			}
 			switch d.MediaType {
 			case "application/vnd.oci.image.manifest.v1+json":
-				m, err := manifestService.Get(context.TODO(), d.Digest)
+				m, err := getManifestCached(context.TODO(), c.manifestCache, manifestService, d.Digest)
 				if err != nil {
 					im.UpdateStatue(v2.Unknown, fmt.Sprintf("get manifests error: %s", err))
 					return
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/distribution/configuration"
+	v2 "github.com/docker/distribution/registry/api/v2"
+)
+
+func newTestController() *MirrorController {
+	return &MirrorController{
+		ctx:             context.Background(),
+		mirrors:         make(map[string]*v2.ImageMirror),
+		pool:            make(map[string]chan struct{}),
+		authChallengers: make(map[string]authChallenger),
+	}
+}
+
+// TestMirrorImageAttachesToInFlightRecord covers the pool's CAS property:
+// two concurrent MirrorImage calls for the same ref must get back the exact
+// same *ImageMirror, with only one of them registering the pool entry (and
+// so starting the worker).
+func TestMirrorImageAttachesToInFlightRecord(t *testing.T) {
+	c := newTestController()
+	name, err := reference.ParseNormalizedNamed("library/alpine")
+	if err != nil {
+		t.Fatalf("parse name: %s", err)
+	}
+
+	ref := name.Name() + ":latest"
+
+	// Simulate a worker already running for ref, without actually spawning
+	// mirrorimages (which would dial out), by doing exactly what MirrorImage
+	// does under its lock for a brand new record.
+	c.mux.Lock()
+	im, _ := c.getimLocked(ref, name, "latest", nil)
+	im.Phase = v2.Mirroring
+	c.pool[ref] = make(chan struct{})
+	c.mux.Unlock()
+
+	c.mux.Lock()
+	attached, isNew := c.getimLocked(ref, name, "latest", nil)
+	_, inflight := c.pool[ref]
+	shouldStart := !inflight && attached.Phase == v2.Pending
+	c.mux.Unlock()
+
+	if isNew {
+		t.Fatal("getimLocked created a second record for an already in-flight ref")
+	}
+	if attached != im {
+		t.Fatal("getimLocked returned a different *ImageMirror for the same ref")
+	}
+	if shouldStart {
+		t.Fatal("shouldStart was true for a ref with a worker already in the pool")
+	}
+}
+
+// TestDelimWaitsForInFlightWorker covers the fix for the regression where
+// DeleteMirrorImage left a stale c.pool[ref] entry behind: deleting a ref
+// whose worker is still running must wait for that worker to release its
+// pool entry, not race ahead and leave the old entry to block a subsequent
+// MirrorImage's shouldStart check.
+func TestDelimWaitsForInFlightWorker(t *testing.T) {
+	c := newTestController()
+	name, err := reference.ParseNormalizedNamed("library/alpine")
+	if err != nil {
+		t.Fatalf("parse name: %s", err)
+	}
+	ref := name.Name() + ":latest"
+
+	c.mux.Lock()
+	im := v2.NewImageMirror(name, "latest")
+	im.Phase = v2.Mirroring
+	c.mirrors[ref] = im
+	done := make(chan struct{})
+	c.pool[ref] = done
+	c.mux.Unlock()
+
+	delimReturned := make(chan struct{})
+	go func() {
+		c.delim(ref)
+		close(delimReturned)
+	}()
+
+	select {
+	case <-delimReturned:
+		t.Fatal("delim returned before the in-flight worker's pool entry was released")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The worker finishes, releasing its pool entry the same way
+	// finishMirroring does.
+	c.mux.Lock()
+	delete(c.pool, ref)
+	close(done)
+	c.mux.Unlock()
+
+	select {
+	case <-delimReturned:
+	case <-time.After(time.Second):
+		t.Fatal("delim did not return after the worker's pool entry was released")
+	}
+
+	c.mux.Lock()
+	_, stillPresent := c.mirrors[ref]
+	c.mux.Unlock()
+	if stillPresent {
+		t.Fatal("delim left the mirror record in place")
+	}
+}
+
+// TestResolveSourcePicksLongestPrefix covers the prefix-matching and
+// RewritePrefix rewriting resolveSource does to pick an upstream source for
+// a repository name.
+func TestResolveSourcePicksLongestPrefix(t *testing.T) {
+	c := newTestController()
+	c.Mirror = configuration.Mirror{
+		Sources: []configuration.MirrorSource{
+			{Name: "default", Host: "https://default.example.com"},
+			{Name: "vendor", Host: "https://vendor.example.com", RepositoryPrefix: "vendor/"},
+			{Name: "vendor-foo", Host: "https://vendor-foo.example.com", RepositoryPrefix: "vendor/foo/", RewritePrefix: "foo/"},
+		},
+	}
+
+	name, err := reference.WithName("vendor/foo/bar")
+	if err != nil {
+		t.Fatalf("parse name: %s", err)
+	}
+	src, upstream, err := c.resolveSource(name)
+	if err != nil {
+		t.Fatalf("resolveSource: %s", err)
+	}
+	if src.Name != "vendor-foo" {
+		t.Errorf("resolveSource picked %q, want %q", src.Name, "vendor-foo")
+	}
+	if upstream.Name() != "foo/bar" {
+		t.Errorf("rewritten upstream name = %q, want %q", upstream.Name(), "foo/bar")
+	}
+
+	name2, err := reference.WithName("vendor/baz")
+	if err != nil {
+		t.Fatalf("parse name: %s", err)
+	}
+	src2, _, err := c.resolveSource(name2)
+	if err != nil {
+		t.Fatalf("resolveSource: %s", err)
+	}
+	if src2.Name != "vendor" {
+		t.Errorf("resolveSource picked %q, want %q", src2.Name, "vendor")
+	}
+
+	name3, err := reference.ParseNormalizedNamed("library/alpine")
+	if err != nil {
+		t.Fatalf("parse name: %s", err)
+	}
+	src3, upstream3, err := c.resolveSource(name3)
+	if err != nil {
+		t.Fatalf("resolveSource: %s", err)
+	}
+	if src3.Name != "default" {
+		t.Errorf("resolveSource picked %q, want %q", src3.Name, "default")
+	}
+	if upstream3.Name() != name3.Name() {
+		t.Errorf("upstream name changed to %q without a RewritePrefix", upstream3.Name())
+	}
+
+	noSources := newTestController()
+	if _, _, err := noSources.resolveSource(name3); err == nil {
+		t.Error("resolveSource: want error when no source is configured, got nil")
+	}
+}
+
+// TestAuthChallengerForCachesPerSource covers that authChallengerFor builds
+// one authChallenger per source Name and reuses it on subsequent calls,
+// rather than sharing a single challenger across every source.
+func TestAuthChallengerForCachesPerSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := newTestController()
+	src := &configuration.MirrorSource{Name: "test-source", Host: srv.URL}
+
+	a1, err := c.authChallengerFor(src)
+	if err != nil {
+		t.Fatalf("authChallengerFor: %s", err)
+	}
+	a2, err := c.authChallengerFor(src)
+	if err != nil {
+		t.Fatalf("authChallengerFor: %s", err)
+	}
+	if a1 != a2 {
+		t.Error("authChallengerFor returned a different authChallenger for the same source on the second call")
+	}
+
+	other := &configuration.MirrorSource{Name: "other-source", Host: srv.URL}
+	a3, err := c.authChallengerFor(other)
+	if err != nil {
+		t.Fatalf("authChallengerFor: %s", err)
+	}
+	if a3 == a1 {
+		t.Error("authChallengerFor returned the same authChallenger for two different sources")
+	}
+}